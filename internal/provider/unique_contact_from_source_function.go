@@ -0,0 +1,302 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/go-getter"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the desired interfaces.
+var _ function.Function = &UniqueContactFromSourceFunction{}
+
+type UniqueContactFromSourceModel struct {
+	Source               types.String `tfsdk:"source"`
+	Delimiter            types.String `tfsdk:"delimiter"`
+	HasHeader            types.Bool   `tfsdk:"has_header"`
+	GroupByField         types.String `tfsdk:"group_by_field"`
+	CodeField            types.String `tfsdk:"code_field"`
+	DestinationField     types.String `tfsdk:"destination_field"`
+	Labels               types.List   `tfsdk:"label_fields"`
+	Variables            types.List   `tfsdk:"variable_fields"`
+	DestinationDedupKeys types.List   `tfsdk:"destination_dedup_fields"`
+	DestinationFormat    types.String `tfsdk:"destination_format"`
+	DefaultRegion        types.String `tfsdk:"default_region"`
+	OnInvalid            types.String `tfsdk:"on_invalid"`
+}
+
+// defaultCSVDelimiter is used when the caller does not set `delimiter`.
+const defaultCSVDelimiter = ","
+
+type UniqueContactFromSourceFunction struct{}
+
+func NewUniqueContactFromSourceFunction() function.Function {
+	return &UniqueContactFromSourceFunction{}
+}
+
+func (f *UniqueContactFromSourceFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "unique_contact_from_source"
+}
+
+func (f *UniqueContactFromSourceFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Compute contacts data without duplicates from a remote CSV source",
+		Description: "Downloads a CSV file with any go-getter single-file address (local path, http(s)://, s3::, gcs::, " +
+			"optionally with a subpath and a `?checksum=` query argument), then merges contacts with duplicate names " +
+			"the same way `unique_contact` does. Directory-oriented getters such as git:: are not supported, since " +
+			"they cannot fetch a single file.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "source",
+				Description: "go-getter address of the CSV file, e.g. `s3::https://bucket.s3.amazonaws.com/contacts.csv?checksum=sha256:...`.",
+			},
+			function.StringParameter{
+				Name:           "delimiter",
+				AllowNullValue: true,
+				Description:    "Single-character CSV field delimiter. Defaults to \",\".",
+			},
+			function.BoolParameter{
+				Name:           "has_header",
+				AllowNullValue: true,
+				Description:    "Whether the first row is a header naming the fields. Defaults to true.",
+			},
+			function.StringParameter{
+				Name: "group_by_field",
+			},
+			function.StringParameter{
+				Name: "code_field",
+			},
+			function.StringParameter{
+				Name: "destination_field",
+			},
+			function.ListParameter{
+				Name:        "label_fields",
+				ElementType: types.StringType,
+			},
+			function.ListParameter{
+				Name:        "variable_fields",
+				ElementType: types.StringType,
+			},
+			function.ListParameter{
+				Name:           "destination_dedup_fields",
+				ElementType:    types.StringType,
+				AllowNullValue: true,
+				Description:    "Fields (from `code`, `destination`) that form the uniqueness key for the `destinations` set. Defaults to [\"code\", \"destination\"].",
+			},
+			function.StringParameter{
+				Name:           "destination_format",
+				AllowNullValue: true,
+				Description:    "How to normalize `destination` values: \"raw\" (default), \"e164\", or \"e164_strict\".",
+			},
+			function.StringParameter{
+				Name:           "default_region",
+				AllowNullValue: true,
+				Description:    "ISO 3166-1 alpha-2 region used to parse destinations that are not already in international format, e.g. \"UA\".",
+			},
+			function.StringParameter{
+				Name:           "on_invalid",
+				AllowNullValue: true,
+				Description:    "How to handle rows that fail validation: \"skip\" (default, warn and drop the row), \"fail\" (return a function error naming the first offending row), or \"collect\" (drop the row and report it in the `errors` return field).",
+			},
+		},
+		Return: function.ObjectReturn{
+			AttrTypes: functionReturnSchema().AttrTypes,
+		},
+	}
+}
+
+func (f *UniqueContactFromSourceFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	// Read Terraform argument data into the variables
+	var data UniqueContactFromSourceModel
+	if err := req.Arguments.Get(ctx, &data.Source, &data.Delimiter, &data.HasHeader, &data.GroupByField, &data.CodeField, &data.DestinationField, &data.Labels, &data.Variables, &data.DestinationDedupKeys, &data.DestinationFormat, &data.DefaultRegion, &data.OnInvalid); err != nil {
+		resp.Error = function.ConcatFuncErrors(resp.Error, err)
+
+		return
+	}
+
+	onInvalid := onInvalidSkip
+	if !data.OnInvalid.IsNull() && !data.OnInvalid.IsUnknown() && data.OnInvalid.ValueString() != "" {
+		onInvalid = data.OnInvalid.ValueString()
+	}
+
+	dedupFields := defaultDestinationDedupFields
+	if !data.DestinationDedupKeys.IsNull() && !data.DestinationDedupKeys.IsUnknown() {
+		fields, err := listToLabels(data.DestinationDedupKeys)
+		if err != nil {
+			resp.Error = function.ConcatFuncErrors(resp.Error, function.NewFuncError(err.Error()))
+
+			return
+		}
+
+		if len(fields) > 0 {
+			dedupFields = fields
+		}
+	}
+
+	destinationFormat := destinationFormatRaw
+	if !data.DestinationFormat.IsNull() && !data.DestinationFormat.IsUnknown() && data.DestinationFormat.ValueString() != "" {
+		destinationFormat = data.DestinationFormat.ValueString()
+	}
+
+	defaultRegion := ""
+	if !data.DefaultRegion.IsNull() && !data.DefaultRegion.IsUnknown() {
+		defaultRegion = data.DefaultRegion.ValueString()
+	}
+
+	delimiter := defaultCSVDelimiter
+	if !data.Delimiter.IsNull() && !data.Delimiter.IsUnknown() && data.Delimiter.ValueString() != "" {
+		delimiter = data.Delimiter.ValueString()
+	}
+
+	hasHeader := true
+	if !data.HasHeader.IsNull() && !data.HasHeader.IsUnknown() {
+		hasHeader = data.HasHeader.ValueBool()
+	}
+
+	labelFields, err := listToLabels(data.Labels)
+	if err != nil {
+		resp.Error = function.ConcatFuncErrors(resp.Error, function.NewFuncError(err.Error()))
+
+		return
+	}
+
+	variableFields, err := listToLabels(data.Variables)
+	if err != nil {
+		resp.Error = function.ConcatFuncErrors(resp.Error, function.NewFuncError(err.Error()))
+
+		return
+	}
+
+	path, cleanup, err := fetchSource(ctx, data.Source.ValueString())
+	if err != nil {
+		resp.Error = function.ConcatFuncErrors(resp.Error, function.NewFuncError(fmt.Sprintf("fetching %q: %s", data.Source.ValueString(), err)))
+
+		return
+	}
+	defer cleanup()
+
+	elements, err := readCSVFile(path, delimiter, hasHeader)
+	if err != nil {
+		resp.Error = function.ConcatFuncErrors(resp.Error, function.NewFuncError(fmt.Sprintf("parsing %q: %s", data.Source.ValueString(), err)))
+
+		return
+	}
+
+	contacts, rowErrors, funcErr := groupContacts(ctx, elements, groupContactsOptions{
+		groupByField:      data.GroupByField.ValueString(),
+		codeField:         data.CodeField.ValueString(),
+		destinationField:  data.DestinationField.ValueString(),
+		labelFields:       labelFields,
+		variableFields:    variableFields,
+		dedupFields:       dedupFields,
+		destinationFormat: destinationFormat,
+		defaultRegion:     defaultRegion,
+		onInvalid:         onInvalid,
+	})
+	if funcErr != nil {
+		resp.Error = function.ConcatFuncErrors(resp.Error, funcErr)
+
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, functionReturnValue(contacts, rowErrors)))
+}
+
+// fetchSource downloads src, which may be any go-getter single-file address
+// (local path, http(s)://, s3::, gcs::, with an optional subpath and
+// `?checksum=` query argument), into a temporary directory and returns the
+// path to the downloaded file along with a cleanup func that removes the
+// temporary directory. The caller must call cleanup once it is done
+// reading the file. Directory-oriented getters such as git:: are not
+// supported, since client.Get is forced into ClientModeFile.
+func fetchSource(ctx context.Context, src string) (string, func(), error) {
+	tmpDir, err := os.MkdirTemp("", "webitel-unique-contact-")
+	if err != nil {
+		return "", func() {}, fmt.Errorf("creating temp dir: %w", err)
+	}
+
+	cleanup := func() { os.RemoveAll(tmpDir) }
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		cleanup()
+
+		return "", func() {}, fmt.Errorf("getting working directory: %w", err)
+	}
+
+	dst := filepath.Join(tmpDir, "source.csv")
+
+	client := &getter.Client{
+		Ctx:  ctx,
+		Src:  src,
+		Dst:  dst,
+		Pwd:  pwd,
+		Mode: getter.ClientModeFile,
+	}
+
+	if err := client.Get(); err != nil {
+		cleanup()
+
+		return "", func() {}, fmt.Errorf("downloading source: %w", err)
+	}
+
+	return dst, cleanup, nil
+}
+
+// readCSVFile parses the CSV file at path using delimiter as the field
+// separator, treating the first row as a header when hasHeader is true. It
+// returns one map per data row, keyed by header name when hasHeader is
+// true, or by the zero-based column index otherwise.
+func readCSVFile(path string, delimiter string, hasHeader bool) ([]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening file: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comma = []rune(delimiter)[0]
+
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading csv: %w", err)
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	header := rows[0]
+	if !hasHeader {
+		header = make([]string, len(rows[0]))
+		for i := range header {
+			header[i] = fmt.Sprintf("%d", i)
+		}
+	} else {
+		rows = rows[1:]
+	}
+
+	elements := make([]map[string]string, 0, len(rows))
+	for _, row := range rows {
+		element := make(map[string]string, len(header))
+		for i, value := range row {
+			if i >= len(header) {
+				break
+			}
+
+			element[header[i]] = value
+		}
+
+		elements = append(elements, element)
+	}
+
+	return elements, nil
+}
@@ -0,0 +1,124 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+func TestUniqueContactFromSourceFunction_Known(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "contacts.csv")
+	if err := os.WriteFile(source, []byte("name,code,destination\nfoo1,1,ami-54d2a63b\nfoo1,1,ami-54d2a63c\nfoo1,2,ami-54d2a63b\nbar1,m3.large,ami-54d2a63b\n"), 0o600); err != nil {
+		t.Fatalf("writing fixture CSV: %s", err)
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+				output "test" {
+					value = provider::webitel::unique_contact_from_source(%q, null, null, "name", "code", "destination", ["code", "destination"], ["name"], null, null, null, null)
+				}
+				`, source),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownOutputValue("test", knownvalue.ObjectExact(map[string]knownvalue.Check{
+						"errors": knownvalue.ListExact([]knownvalue.Check{}),
+						"contacts": knownvalue.MapExact(map[string]knownvalue.Check{
+							"bar1": knownvalue.ObjectExact(map[string]knownvalue.Check{
+								"labels":    knownvalue.ListExact([]knownvalue.Check{knownvalue.StringExact("m3.large"), knownvalue.StringExact("ami-54d2a63b")}),
+								"variables": knownvalue.MapExact(map[string]knownvalue.Check{"name": knownvalue.StringExact("bar1")}),
+								"destinations": knownvalue.ListExact([]knownvalue.Check{
+									knownvalue.ObjectExact(map[string]knownvalue.Check{
+										"code":        knownvalue.StringExact("m3.large"),
+										"destination": knownvalue.StringExact("ami-54d2a63b"),
+									}),
+								}),
+							}),
+							"foo1": knownvalue.ObjectExact(map[string]knownvalue.Check{
+								"labels": knownvalue.ListExact([]knownvalue.Check{
+									knownvalue.StringExact("1"), knownvalue.StringExact("ami-54d2a63b"),
+									knownvalue.StringExact("ami-54d2a63c"), knownvalue.StringExact("2"),
+								}),
+								"variables": knownvalue.MapExact(map[string]knownvalue.Check{"name": knownvalue.StringExact("foo1")}),
+								"destinations": knownvalue.ListExact([]knownvalue.Check{
+									knownvalue.ObjectExact(map[string]knownvalue.Check{
+										"code":        knownvalue.StringExact("1"),
+										"destination": knownvalue.StringExact("ami-54d2a63b"),
+									}),
+									knownvalue.ObjectExact(map[string]knownvalue.Check{
+										"code":        knownvalue.StringExact("1"),
+										"destination": knownvalue.StringExact("ami-54d2a63c"),
+									}),
+									knownvalue.ObjectExact(map[string]knownvalue.Check{
+										"code":        knownvalue.StringExact("2"),
+										"destination": knownvalue.StringExact("ami-54d2a63b"),
+									}),
+								}),
+							}),
+						}),
+					})),
+				},
+			},
+		},
+	})
+}
+
+// TestUniqueContactFromSourceFunction_HTTP proves that fetchSource works
+// against a non-local go-getter scheme, not just plain local paths.
+func TestUniqueContactFromSourceFunction_HTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("name,code,destination\nfoo1,1,ami-54d2a63b\n"))
+	}))
+	defer server.Close()
+
+	source := server.URL + "/contacts.csv"
+
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+				output "test" {
+					value = provider::webitel::unique_contact_from_source(%q, null, null, "name", "code", "destination", ["code", "destination"], ["name"], null, null, null, null)
+				}
+				`, source),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownOutputValue("test", knownvalue.ObjectExact(map[string]knownvalue.Check{
+						"errors": knownvalue.ListExact([]knownvalue.Check{}),
+						"contacts": knownvalue.MapExact(map[string]knownvalue.Check{
+							"foo1": knownvalue.ObjectExact(map[string]knownvalue.Check{
+								"labels":    knownvalue.ListExact([]knownvalue.Check{knownvalue.StringExact("1"), knownvalue.StringExact("ami-54d2a63b")}),
+								"variables": knownvalue.MapExact(map[string]knownvalue.Check{"name": knownvalue.StringExact("foo1")}),
+								"destinations": knownvalue.ListExact([]knownvalue.Check{
+									knownvalue.ObjectExact(map[string]knownvalue.Check{
+										"code":        knownvalue.StringExact("1"),
+										"destination": knownvalue.StringExact("ami-54d2a63b"),
+									}),
+								}),
+							}),
+						}),
+					})),
+				},
+			},
+		},
+	})
+}
@@ -5,26 +5,59 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/function"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/nyaruka/phonenumbers"
 )
 
 // Ensure the implementation satisfies the desired interfaces.
 var _ function.Function = &UniqueContactFunction{}
 
 type UniqueContactModel struct {
-	CSV              types.List   `tfsdk:"csv"`
-	GroupByField     types.String `tfsdk:"group_by_field"`
-	CodeField        types.String `tfsdk:"code_field"`
-	DestinationField types.String `tfsdk:"destination_field"`
-	Labels           types.List   `tfsdk:"label_fields"`
-	Variables        types.List   `tfsdk:"variable_fields"`
+	CSV                  types.List   `tfsdk:"csv"`
+	GroupByField         types.String `tfsdk:"group_by_field"`
+	CodeField            types.String `tfsdk:"code_field"`
+	DestinationField     types.String `tfsdk:"destination_field"`
+	Labels               types.List   `tfsdk:"label_fields"`
+	Variables            types.List   `tfsdk:"variable_fields"`
+	DestinationDedupKeys types.List   `tfsdk:"destination_dedup_fields"`
+	DestinationFormat    types.String `tfsdk:"destination_format"`
+	DefaultRegion        types.String `tfsdk:"default_region"`
+	OnInvalid            types.String `tfsdk:"on_invalid"`
 }
 
+// Supported values for the destination_format parameter.
+const (
+	destinationFormatRaw        = "raw"
+	destinationFormatE164       = "e164"
+	destinationFormatE164Strict = "e164_strict"
+)
+
+// Supported values for the on_invalid parameter.
+const (
+	onInvalidSkip    = "skip"
+	onInvalidFail    = "fail"
+	onInvalidCollect = "collect"
+)
+
+// defaultDestinationDedupFields is the uniqueness key used for the
+// `destinations` set when the caller does not override
+// `destination_dedup_fields`, preserving the historical (code, destination)
+// behavior.
+var defaultDestinationDedupFields = []string{"code", "destination"}
+
+// destinationDedupKeySeparator joins the values of the chosen dedup fields
+// into a single map key. It is the ASCII unit separator, a control character
+// that ordinary CSV field content does not use; field values are joined as
+// they are, without escaping.
+const destinationDedupKeySeparator = "\x1f"
+
 type UniqueContactFunction struct{}
 
 func NewUniqueContactFunction() function.Function {
@@ -63,9 +96,30 @@ func (f *UniqueContactFunction) Definition(ctx context.Context, req function.Def
 				Name:        "variable_fields",
 				ElementType: types.StringType,
 			},
+			function.ListParameter{
+				Name:           "destination_dedup_fields",
+				ElementType:    types.StringType,
+				AllowNullValue: true,
+				Description:    "Fields (from `code`, `destination`) that form the uniqueness key for the `destinations` set. Defaults to [\"code\", \"destination\"].",
+			},
+			function.StringParameter{
+				Name:           "destination_format",
+				AllowNullValue: true,
+				Description:    "How to normalize `destination` values: \"raw\" (default), \"e164\", or \"e164_strict\".",
+			},
+			function.StringParameter{
+				Name:           "default_region",
+				AllowNullValue: true,
+				Description:    "ISO 3166-1 alpha-2 region used to parse destinations that are not already in international format, e.g. \"UA\".",
+			},
+			function.StringParameter{
+				Name:           "on_invalid",
+				AllowNullValue: true,
+				Description:    "How to handle rows that fail validation: \"skip\" (default, warn and drop the row), \"fail\" (return a function error naming the first offending row), or \"collect\" (drop the row and report it in the `errors` return field).",
+			},
 		},
-		Return: function.MapReturn{
-			ElementType: returnSchema(),
+		Return: function.ObjectReturn{
+			AttrTypes: functionReturnSchema().AttrTypes,
 		},
 	}
 }
@@ -95,17 +149,90 @@ func returnSchema() types.ObjectType {
 	}
 }
 
-func (f *UniqueContactFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
-	schema := returnSchema()
+func errorSchema() types.ObjectType {
+	return types.ObjectType{
+		AttrTypes: map[string]attr.Type{
+			"row":    types.Int64Type,
+			"field":  types.StringType,
+			"reason": types.StringType,
+		},
+	}
+}
+
+// functionReturnSchema is the top-level object returned by unique_contact
+// and unique_contact_from_source: the deduplicated contacts keyed by
+// group_by_field value, plus the rows dropped for failing validation when
+// on_invalid is "collect" (otherwise always empty).
+func functionReturnSchema() types.ObjectType {
+	return types.ObjectType{
+		AttrTypes: map[string]attr.Type{
+			"contacts": types.MapType{
+				ElemType: returnSchema(),
+			},
+			"errors": types.ListType{
+				ElemType: errorSchema(),
+			},
+		},
+	}
+}
+
+// rowValidationError records why a CSV row was rejected.
+type rowValidationError struct {
+	row    int
+	field  string
+	reason string
+}
+
+func (e rowValidationError) message() string {
+	return fmt.Sprintf("row %d: %s: %s", e.row, e.field, e.reason)
+}
 
+func (e rowValidationError) attrValue() attr.Value {
+	return types.ObjectValueMust(errorSchema().AttrTypes, map[string]attr.Value{
+		"row":    types.Int64Value(int64(e.row)),
+		"field":  types.StringValue(e.field),
+		"reason": types.StringValue(e.reason),
+	})
+}
+
+func (f *UniqueContactFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
 	// Read Terraform argument data into the variables
 	var data UniqueContactModel
-	if err := req.Arguments.Get(ctx, &data.CSV, &data.GroupByField, &data.CodeField, &data.DestinationField, &data.Labels, &data.Variables); err != nil {
+	if err := req.Arguments.Get(ctx, &data.CSV, &data.GroupByField, &data.CodeField, &data.DestinationField, &data.Labels, &data.Variables, &data.DestinationDedupKeys, &data.DestinationFormat, &data.DefaultRegion, &data.OnInvalid); err != nil {
 		resp.Error = function.ConcatFuncErrors(resp.Error, err)
 
 		return
 	}
 
+	onInvalid := onInvalidSkip
+	if !data.OnInvalid.IsNull() && !data.OnInvalid.IsUnknown() && data.OnInvalid.ValueString() != "" {
+		onInvalid = data.OnInvalid.ValueString()
+	}
+
+	destinationFormat := destinationFormatRaw
+	if !data.DestinationFormat.IsNull() && !data.DestinationFormat.IsUnknown() && data.DestinationFormat.ValueString() != "" {
+		destinationFormat = data.DestinationFormat.ValueString()
+	}
+
+	defaultRegion := ""
+	if !data.DefaultRegion.IsNull() && !data.DefaultRegion.IsUnknown() {
+		defaultRegion = data.DefaultRegion.ValueString()
+	}
+
+	dedupFields := defaultDestinationDedupFields
+	if !data.DestinationDedupKeys.IsNull() && !data.DestinationDedupKeys.IsUnknown() {
+		fields, err := listToLabels(data.DestinationDedupKeys)
+		if err != nil {
+			resp.Error = function.ConcatFuncErrors(resp.Error, function.NewFuncError(err.Error()))
+
+			return
+		}
+
+		if len(fields) > 0 {
+			dedupFields = fields
+		}
+	}
+
 	var elements []map[string]string
 	diag := data.CSV.ElementsAs(ctx, &elements, true)
 	if diag.HasError() {
@@ -128,6 +255,85 @@ func (f *UniqueContactFunction) Run(ctx context.Context, req function.RunRequest
 		return
 	}
 
+	contacts, rowErrors, funcErr := groupContacts(ctx, elements, groupContactsOptions{
+		groupByField:      data.GroupByField.ValueString(),
+		codeField:         data.CodeField.ValueString(),
+		destinationField:  data.DestinationField.ValueString(),
+		labelFields:       labelFields,
+		variableFields:    variableFields,
+		dedupFields:       dedupFields,
+		destinationFormat: destinationFormat,
+		defaultRegion:     defaultRegion,
+		onInvalid:         onInvalid,
+	})
+	if funcErr != nil {
+		resp.Error = function.ConcatFuncErrors(resp.Error, funcErr)
+
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, functionReturnValue(contacts, rowErrors)))
+}
+
+// functionReturnValue assembles the {contacts, errors} object returned by
+// unique_contact and unique_contact_from_source.
+func functionReturnValue(contacts map[string]attr.Value, rowErrors []rowValidationError) attr.Value {
+	errorValues := make([]attr.Value, 0, len(rowErrors))
+	for _, e := range rowErrors {
+		errorValues = append(errorValues, e.attrValue())
+	}
+
+	return types.ObjectValueMust(functionReturnSchema().AttrTypes, map[string]attr.Value{
+		"contacts": types.MapValueMust(returnSchema(), contacts),
+		"errors":   types.ListValueMust(errorSchema(), errorValues),
+	})
+}
+
+// groupContactsOptions configures groupContacts. It mirrors the subset of
+// UniqueContactModel that both unique_contact and
+// unique_contact_from_source need in order to group rows into contacts.
+type groupContactsOptions struct {
+	groupByField      string
+	codeField         string
+	destinationField  string
+	labelFields       []string
+	variableFields    []string
+	dedupFields       []string
+	destinationFormat string
+	defaultRegion     string
+	onInvalid         string
+}
+
+// groupContacts merges rows sharing the same group_by_field value into a
+// single contact, deduplicating destinations on dedupFields and labels on
+// their string value. It is shared by every provider function that turns
+// tabular contact data into the unique_contact return shape.
+//
+// Rows failing validation (empty group_by_field, missing code_field or
+// destination_field, or an unparseable destination in "e164_strict" mode)
+// are handled per opts.onInvalid: "skip" drops the row with a warning,
+// "fail" returns a *function.FuncError naming the first offending row, and
+// "collect" drops the row and reports it in the returned
+// []rowValidationError.
+func groupContacts(ctx context.Context, elements []map[string]string, opts groupContactsOptions) (map[string]attr.Value, []rowValidationError, *function.FuncError) {
+	for _, field := range opts.dedupFields {
+		if field != "code" && field != "destination" {
+			return nil, nil, function.NewFuncError(fmt.Sprintf("destination_dedup_fields: unsupported field %q, must be one of \"code\", \"destination\"", field))
+		}
+	}
+
+	switch opts.destinationFormat {
+	case "", destinationFormatRaw, destinationFormatE164, destinationFormatE164Strict:
+	default:
+		return nil, nil, function.NewFuncError(fmt.Sprintf("destination_format: unsupported value %q, must be one of \"raw\", \"e164\", \"e164_strict\"", opts.destinationFormat))
+	}
+
+	switch opts.onInvalid {
+	case "", onInvalidSkip, onInvalidFail, onInvalidCollect:
+	default:
+		return nil, nil, function.NewFuncError(fmt.Sprintf("on_invalid: unsupported value %q, must be one of \"skip\", \"fail\", \"collect\"", opts.onInvalid))
+	}
+
 	type (
 		destination struct {
 			code        string
@@ -143,11 +349,46 @@ func (f *UniqueContactFunction) Run(ctx context.Context, req function.RunRequest
 		}
 	)
 
+	var rowErrors []rowValidationError
+
 	seen := make(map[string]contact)
 	for i, v := range elements {
-		name := stripSpaces(v[data.GroupByField.ValueString()])
+		name := stripSpaces(v[opts.groupByField])
+
+		var invalid []rowValidationError
 		if name == "" {
-			tflog.Warn(ctx, "element has empty name", map[string]interface{}{"i": i, "name": name})
+			invalid = append(invalid, rowValidationError{row: i, field: "group_by_field", reason: "empty group_by_field value"})
+		}
+
+		if v[opts.codeField] == "" {
+			invalid = append(invalid, rowValidationError{row: i, field: "code_field", reason: "missing code_field value"})
+		}
+
+		rawDestination := v[opts.destinationField]
+		if rawDestination == "" {
+			invalid = append(invalid, rowValidationError{row: i, field: "destination_field", reason: "missing destination_field value"})
+		}
+
+		var normalizedDestination string
+		if len(invalid) == 0 {
+			var rowErr *rowValidationError
+			normalizedDestination, rowErr = normalizeDestination(ctx, rawDestination, opts.destinationFormat, opts.defaultRegion, i)
+			if rowErr != nil {
+				invalid = append(invalid, *rowErr)
+			}
+		}
+
+		if len(invalid) > 0 {
+			switch opts.onInvalid {
+			case onInvalidFail:
+				return nil, nil, function.NewFuncError(invalid[0].message())
+			case onInvalidCollect:
+				rowErrors = append(rowErrors, invalid...)
+			default:
+				for _, e := range invalid {
+					tflog.Warn(ctx, "skipping invalid row", map[string]interface{}{"row": e.row, "field": e.field, "reason": e.reason})
+				}
+			}
 
 			continue
 		}
@@ -163,14 +404,14 @@ func (f *UniqueContactFunction) Run(ctx context.Context, req function.RunRequest
 
 		seen[name].mu.Lock()
 
-		labels := make([]string, 0, len(labelFields))
-		for _, field := range labelFields {
+		labels := make([]string, 0, len(opts.labelFields))
+		for _, field := range opts.labelFields {
 			labels = append(labels, v[field])
 		}
 
 		d := destination{
-			code:        v[data.CodeField.ValueString()],
-			destination: v[data.DestinationField.ValueString()],
+			code:        v[opts.codeField],
+			destination: normalizedDestination,
 		}
 
 		c := contact{
@@ -180,7 +421,7 @@ func (f *UniqueContactFunction) Run(ctx context.Context, req function.RunRequest
 			variables:    seen[name].variables,
 		}
 
-		for _, field := range variableFields {
+		for _, field := range opts.variableFields {
 			if _, ok := c.variables[field]; ok {
 				tflog.Warn(ctx, "variable already exists, overwriting", map[string]interface{}{"key": field})
 			}
@@ -192,6 +433,23 @@ func (f *UniqueContactFunction) Run(ctx context.Context, req function.RunRequest
 		seen[name].mu.Unlock()
 	}
 
+	destKey := func(dest destination) string {
+		parts := make([]string, 0, len(opts.dedupFields))
+		for _, field := range opts.dedupFields {
+			switch field {
+			case "code":
+				parts = append(parts, dest.code)
+			case "destination":
+				parts = append(parts, dest.destination)
+			default:
+				parts = append(parts, "")
+			}
+		}
+
+		return strings.Join(parts, destinationDedupKeySeparator)
+	}
+
+	schema := returnSchema()
 	contacts := make(map[string]attr.Value, len(seen))
 	for n, c := range seen {
 		labels := make([]attr.Value, 0, len(c.labels))
@@ -211,10 +469,10 @@ func (f *UniqueContactFunction) Run(ctx context.Context, req function.RunRequest
 
 		destinations := make([]attr.Value, 0, len(c.destinations))
 
-		// TODO: Make unique destination list based on `code` and `destination`
 		seenDestination := make(map[string]bool, len(c.destinations))
 		for _, dest := range c.destinations {
-			if !seenDestination[dest.destination] {
+			key := destKey(dest)
+			if !seenDestination[key] {
 				obj := types.ObjectValueMust(destinationSchema().AttrTypes, map[string]attr.Value{
 					"code":        types.StringValue(dest.code),
 					"destination": types.StringValue(dest.destination),
@@ -223,7 +481,7 @@ func (f *UniqueContactFunction) Run(ctx context.Context, req function.RunRequest
 				destinations = append(destinations, obj)
 			}
 
-			seenDestination[dest.destination] = true
+			seenDestination[key] = true
 		}
 
 		contacts[n] = types.ObjectValueMust(schema.AttrTypes, map[string]attr.Value{
@@ -233,24 +491,35 @@ func (f *UniqueContactFunction) Run(ctx context.Context, req function.RunRequest
 		})
 	}
 
-	// Set the result
-	// "foo bar": {
-	// 		"labels": ["one", "foo", "bar"],
-	// 		"variables": [
-	// 			{
-	// 				"key": "foo",
-	// 				"value": "bar"
-	// 			}
-	// 		],
-	// 		"destinations": [
-	// 			{
-	// 				"code": "1",
-	// 				"destination": "123"
-	// 			}
-	// 		]
-	// }
-	// m := map[string]map[string]any{}
-	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, types.MapValueMust(returnSchema(), contacts)))
+	return contacts, rowErrors, nil
+}
+
+// normalizeDestination applies destinationFormat to raw. In "raw" mode
+// (the default) it is a no-op. In "e164" mode, unparseable values are
+// passed through unchanged with a warning. In "e164_strict" mode,
+// unparseable values produce a *rowValidationError naming rowIndex and the
+// offending value, which the caller handles per opts.onInvalid.
+func normalizeDestination(ctx context.Context, raw string, destinationFormat string, defaultRegion string, rowIndex int) (string, *rowValidationError) {
+	if destinationFormat == "" || destinationFormat == destinationFormatRaw {
+		return raw, nil
+	}
+
+	num, err := phonenumbers.Parse(raw, defaultRegion)
+	if err != nil {
+		if destinationFormat == destinationFormatE164Strict {
+			return raw, &rowValidationError{
+				row:    rowIndex,
+				field:  "destination_field",
+				reason: fmt.Sprintf("destination %q is not a valid phone number: %s", raw, err),
+			}
+		}
+
+		tflog.Warn(ctx, "destination is not a valid phone number, passing through raw value", map[string]interface{}{"row": rowIndex, "destination": raw, "error": err.Error()})
+
+		return raw, nil
+	}
+
+	return phonenumbers.Format(num, phonenumbers.E164), nil
 }
 
 func UniqueSliceElements[T comparable](inputSlice []T) []T {
@@ -32,48 +32,305 @@ func TestUniqueContactFunction_Known(t *testing.T) {
 						foo1,2,ami-54d2a63b
 						bar1,m3.large,ami-54d2a63b
 				  	CSV
-				
+
 				  	instances = csvdecode(local.csv_data)
 				}
 
 				output "test" {
-					value = provider::webitel::unique_contact(local.instances, "name", "code", "destination", ["code", "destination"], ["name"])
+					value = provider::webitel::unique_contact(local.instances, "name", "code", "destination", ["code", "destination"], ["name"], null, null, null, null)
 				}
 				`,
 				ConfigStateChecks: []statecheck.StateCheck{
-					statecheck.ExpectKnownOutputValue("test", knownvalue.MapExact(map[string]knownvalue.Check{
-						"bar1": knownvalue.ObjectExact(map[string]knownvalue.Check{
-							"labels":    knownvalue.ListExact([]knownvalue.Check{knownvalue.StringExact("m3.large"), knownvalue.StringExact("ami-54d2a63b")}),
-							"variables": knownvalue.MapExact(map[string]knownvalue.Check{"name": knownvalue.StringExact("bar1")}),
-							"destinations": knownvalue.ListExact([]knownvalue.Check{
-								knownvalue.ObjectExact(map[string]knownvalue.Check{
-									"code":        knownvalue.StringExact("m3.large"),
-									"destination": knownvalue.StringExact("ami-54d2a63b"),
+					statecheck.ExpectKnownOutputValue("test", knownvalue.ObjectExact(map[string]knownvalue.Check{
+						"errors": knownvalue.ListExact([]knownvalue.Check{}),
+						"contacts": knownvalue.MapExact(map[string]knownvalue.Check{
+							"bar1": knownvalue.ObjectExact(map[string]knownvalue.Check{
+								"labels":    knownvalue.ListExact([]knownvalue.Check{knownvalue.StringExact("m3.large"), knownvalue.StringExact("ami-54d2a63b")}),
+								"variables": knownvalue.MapExact(map[string]knownvalue.Check{"name": knownvalue.StringExact("bar1")}),
+								"destinations": knownvalue.ListExact([]knownvalue.Check{
+									knownvalue.ObjectExact(map[string]knownvalue.Check{
+										"code":        knownvalue.StringExact("m3.large"),
+										"destination": knownvalue.StringExact("ami-54d2a63b"),
+									}),
+								}),
+							}),
+							"foo1": knownvalue.ObjectExact(map[string]knownvalue.Check{
+								"labels": knownvalue.ListExact([]knownvalue.Check{
+									knownvalue.StringExact("1"), knownvalue.StringExact("ami-54d2a63b"),
+									knownvalue.StringExact("ami-54d2a63c"), knownvalue.StringExact("2"),
+								}),
+								"variables": knownvalue.MapExact(map[string]knownvalue.Check{"name": knownvalue.StringExact("foo1")}),
+								"destinations": knownvalue.ListExact([]knownvalue.Check{
+									knownvalue.ObjectExact(map[string]knownvalue.Check{
+										"code":        knownvalue.StringExact("1"),
+										"destination": knownvalue.StringExact("ami-54d2a63b"),
+									}),
+									knownvalue.ObjectExact(map[string]knownvalue.Check{
+										"code":        knownvalue.StringExact("1"),
+										"destination": knownvalue.StringExact("ami-54d2a63c"),
+									}),
+									knownvalue.ObjectExact(map[string]knownvalue.Check{
+										"code":        knownvalue.StringExact("2"),
+										"destination": knownvalue.StringExact("ami-54d2a63b"),
+									}),
 								}),
 							}),
 						}),
-						"foo1": knownvalue.ObjectExact(map[string]knownvalue.Check{
-							"labels": knownvalue.ListExact([]knownvalue.Check{
-								knownvalue.StringExact("1"), knownvalue.StringExact("ami-54d2a63b"),
-								knownvalue.StringExact("ami-54d2a63c"), knownvalue.StringExact("2"),
+					})),
+				},
+			},
+		},
+	})
+}
+
+// TestUniqueContactFunction_DestinationDedupFields proves that callers can
+// narrow the `destinations` uniqueness key down to `destination` alone,
+// collapsing entries that only differ by `code`.
+func TestUniqueContactFunction_DestinationDedupFields(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+				locals {
+				  	csv_data = <<-CSV
+						name,code,destination
+						foo1,1,ami-54d2a63b
+						foo1,1,ami-54d2a63c
+						foo1,2,ami-54d2a63b
+				  	CSV
+
+				  	instances = csvdecode(local.csv_data)
+				}
+
+				output "test" {
+					value = provider::webitel::unique_contact(local.instances, "name", "code", "destination", ["code", "destination"], ["name"], ["destination"], null, null, null)
+				}
+				`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownOutputValue("test", knownvalue.ObjectExact(map[string]knownvalue.Check{
+						"errors": knownvalue.ListExact([]knownvalue.Check{}),
+						"contacts": knownvalue.MapExact(map[string]knownvalue.Check{
+							"foo1": knownvalue.ObjectExact(map[string]knownvalue.Check{
+								"labels": knownvalue.ListExact([]knownvalue.Check{
+									knownvalue.StringExact("1"), knownvalue.StringExact("ami-54d2a63b"),
+									knownvalue.StringExact("ami-54d2a63c"), knownvalue.StringExact("2"),
+								}),
+								"variables": knownvalue.MapExact(map[string]knownvalue.Check{"name": knownvalue.StringExact("foo1")}),
+								"destinations": knownvalue.ListExact([]knownvalue.Check{
+									knownvalue.ObjectExact(map[string]knownvalue.Check{
+										"code":        knownvalue.StringExact("1"),
+										"destination": knownvalue.StringExact("ami-54d2a63b"),
+									}),
+									knownvalue.ObjectExact(map[string]knownvalue.Check{
+										"code":        knownvalue.StringExact("1"),
+										"destination": knownvalue.StringExact("ami-54d2a63c"),
+									}),
+								}),
 							}),
-							"variables": knownvalue.MapExact(map[string]knownvalue.Check{"name": knownvalue.StringExact("foo1")}),
-							"destinations": knownvalue.ListExact([]knownvalue.Check{
-								knownvalue.ObjectExact(map[string]knownvalue.Check{
-									"code":        knownvalue.StringExact("1"),
-									"destination": knownvalue.StringExact("ami-54d2a63b"),
+						}),
+					})),
+				},
+			},
+		},
+	})
+}
+
+// TestUniqueContactFunction_DestinationFormatE164 proves that destinations
+// are normalized to E.164 before being used as the dedup key, so the same
+// number written in two different formats collapses into one entry. Labels
+// keep the raw values.
+func TestUniqueContactFunction_DestinationFormatE164(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+				locals {
+				  	csv_data = <<-CSV
+						name,code,destination
+						foo1,1,+380 44 555-0100
+						foo1,1,380445550100
+				  	CSV
+
+				  	instances = csvdecode(local.csv_data)
+				}
+
+				output "test" {
+					value = provider::webitel::unique_contact(local.instances, "name", "code", "destination", ["code", "destination"], ["name"], null, "e164", "UA", null)
+				}
+				`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownOutputValue("test", knownvalue.ObjectExact(map[string]knownvalue.Check{
+						"errors": knownvalue.ListExact([]knownvalue.Check{}),
+						"contacts": knownvalue.MapExact(map[string]knownvalue.Check{
+							"foo1": knownvalue.ObjectExact(map[string]knownvalue.Check{
+								"labels": knownvalue.ListExact([]knownvalue.Check{
+									knownvalue.StringExact("1"), knownvalue.StringExact("+380 44 555-0100"),
+									knownvalue.StringExact("380445550100"),
+								}),
+								"variables": knownvalue.MapExact(map[string]knownvalue.Check{"name": knownvalue.StringExact("foo1")}),
+								"destinations": knownvalue.ListExact([]knownvalue.Check{
+									knownvalue.ObjectExact(map[string]knownvalue.Check{
+										"code":        knownvalue.StringExact("1"),
+										"destination": knownvalue.StringExact("+380445550100"),
+									}),
 								}),
-								knownvalue.ObjectExact(map[string]knownvalue.Check{
-									"code":        knownvalue.StringExact("1"),
-									"destination": knownvalue.StringExact("ami-54d2a63c"),
+							}),
+						}),
+					})),
+				},
+			},
+		},
+	})
+}
+
+// TestUniqueContactFunction_DestinationFormatE164StrictError proves that an
+// unparseable destination fails the function outright in "e164_strict"
+// mode when on_invalid is explicitly set to "fail" (the default is "skip").
+func TestUniqueContactFunction_DestinationFormatE164StrictError(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+				locals {
+				  	csv_data = <<-CSV
+						name,code,destination
+						foo1,1,ami-54d2a63b
+				  	CSV
+
+				  	instances = csvdecode(local.csv_data)
+				}
+
+				output "test" {
+					value = provider::webitel::unique_contact(local.instances, "name", "code", "destination", ["code", "destination"], ["name"], null, "e164_strict", "UA", "fail")
+				}
+				`,
+				ExpectError: regexp.MustCompile(`not a valid phone number`),
+			},
+		},
+	})
+}
+
+// TestUniqueContactFunction_OnInvalidCollect proves that invalid rows are
+// dropped from `contacts` and reported in `errors` instead of failing the
+// function when on_invalid is "collect".
+func TestUniqueContactFunction_OnInvalidCollect(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+				locals {
+				  	csv_data = <<-CSV
+						name,code,destination
+						,1,ami-54d2a63b
+						foo1,,ami-54d2a63c
+						foo1,2,
+						bar1,3,ami-54d2a63d
+				  	CSV
+
+				  	instances = csvdecode(local.csv_data)
+				}
+
+				output "test" {
+					value = provider::webitel::unique_contact(local.instances, "name", "code", "destination", ["code", "destination"], ["name"], null, null, null, "collect")
+				}
+				`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownOutputValue("test", knownvalue.ObjectExact(map[string]knownvalue.Check{
+						"contacts": knownvalue.MapExact(map[string]knownvalue.Check{
+							"bar1": knownvalue.ObjectExact(map[string]knownvalue.Check{
+								"labels":    knownvalue.ListExact([]knownvalue.Check{knownvalue.StringExact("3"), knownvalue.StringExact("ami-54d2a63d")}),
+								"variables": knownvalue.MapExact(map[string]knownvalue.Check{"name": knownvalue.StringExact("bar1")}),
+								"destinations": knownvalue.ListExact([]knownvalue.Check{
+									knownvalue.ObjectExact(map[string]knownvalue.Check{
+										"code":        knownvalue.StringExact("3"),
+										"destination": knownvalue.StringExact("ami-54d2a63d"),
+									}),
+								}),
+							}),
+						}),
+						"errors": knownvalue.ListExact([]knownvalue.Check{
+							knownvalue.ObjectExact(map[string]knownvalue.Check{
+								"row":    knownvalue.Int64Exact(0),
+								"field":  knownvalue.StringExact("group_by_field"),
+								"reason": knownvalue.StringExact("empty group_by_field value"),
+							}),
+							knownvalue.ObjectExact(map[string]knownvalue.Check{
+								"row":    knownvalue.Int64Exact(1),
+								"field":  knownvalue.StringExact("code_field"),
+								"reason": knownvalue.StringExact("missing code_field value"),
+							}),
+							knownvalue.ObjectExact(map[string]knownvalue.Check{
+								"row":    knownvalue.Int64Exact(2),
+								"field":  knownvalue.StringExact("destination_field"),
+								"reason": knownvalue.StringExact("missing destination_field value"),
+							}),
+						}),
+					})),
+				},
+			},
+		},
+	})
+}
+
+// TestUniqueContactFunction_OnInvalidSkipDefault proves that, when
+// on_invalid is left unset, rows missing code_field or destination_field
+// are dropped from `contacts` with only a log warning, rather than being
+// included with empty-string values (as baseline did) or reported in
+// `errors`.
+func TestUniqueContactFunction_OnInvalidSkipDefault(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+				locals {
+				  	csv_data = <<-CSV
+						name,code,destination
+						foo1,,ami-54d2a63c
+						bar1,3,ami-54d2a63d
+				  	CSV
+
+				  	instances = csvdecode(local.csv_data)
+				}
+
+				output "test" {
+					value = provider::webitel::unique_contact(local.instances, "name", "code", "destination", ["code", "destination"], ["name"], null, null, null, null)
+				}
+				`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownOutputValue("test", knownvalue.ObjectExact(map[string]knownvalue.Check{
+						"contacts": knownvalue.MapExact(map[string]knownvalue.Check{
+							"bar1": knownvalue.ObjectExact(map[string]knownvalue.Check{
+								"labels":    knownvalue.ListExact([]knownvalue.Check{knownvalue.StringExact("3"), knownvalue.StringExact("ami-54d2a63d")}),
+								"variables": knownvalue.MapExact(map[string]knownvalue.Check{"name": knownvalue.StringExact("bar1")}),
+								"destinations": knownvalue.ListExact([]knownvalue.Check{
+									knownvalue.ObjectExact(map[string]knownvalue.Check{
+										"code":        knownvalue.StringExact("3"),
+										"destination": knownvalue.StringExact("ami-54d2a63d"),
+									}),
 								}),
-								// FIXME: Make unique destination list based on `code` and `destination`
-								// knownvalue.ObjectExact(map[string]knownvalue.Check{
-								// 	"code":        knownvalue.StringExact("2"),
-								// 	"destination": knownvalue.StringExact("ami-54d2a63b"),
-								// }),
 							}),
 						}),
+						"errors": knownvalue.ListExact([]knownvalue.Check{}),
 					})),
 				},
 			},
@@ -91,7 +348,7 @@ func TestUniqueContactFunction_Null(t *testing.T) {
 			{
 				Config: `
 				output "test" {
-					value = provider::webitel::unique_contact(null, null, null, null, null, null)
+					value = provider::webitel::unique_contact(null, null, null, null, null, null, null, null, null, null)
 				}
 				`,
 				// The parameter does not enable AllowNullValue